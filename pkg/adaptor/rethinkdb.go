@@ -1,9 +1,15 @@
 package adaptor
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/compose/transporter/pkg/message"
@@ -11,6 +17,155 @@ import (
 	gorethink "github.com/dancannon/gorethink"
 )
 
+// rethinkdbConfig is the Rethinkdb adaptor's configuration. It embeds the
+// common dbConfig (uri, namespace, debug, ...) and adds the knobs needed to
+// connect to a real, secured RethinkDB cluster rather than a single
+// unauthenticated node.
+type rethinkdbConfig struct {
+	dbConfig
+
+	AuthKey       string              `json:"auth_key"`
+	Username      string              `json:"username"`
+	Password      string              `json:"password"`
+	DiscoverHosts bool                `json:"discover_hosts"`
+	MaxOpen       int                 `json:"max_open"`
+	MaxIdle       int                 `json:"max_idle"`
+	IdleTimeout   int                 `json:"idle_timeout"` // seconds
+	Timeout       int                 `json:"timeout"`      // seconds
+	TLSConfig     *rethinkdbTLSConfig `json:"ssl"`
+
+	BulkSize      int     `json:"bulk_size"`
+	FlushInterval float64 `json:"flush_interval"` // seconds, may be fractional
+	Durability    string  `json:"durability"`     // "soft" or "hard"
+	Conflict      string  `json:"conflict"`       // "error", "replace", or "update"
+
+	CreateTable      bool                   `json:"create_table"` // create the table if it's missing, default false
+	DropTable        bool                   `json:"drop_table"`   // drop the table on every Listen(), default false
+	PrimaryKey       string                 `json:"primary_key"`
+	SecondaryIndexes []secondaryIndexConfig `json:"secondary_indexes"`
+}
+
+// rethinkdbTLSConfig points at the PEM-encoded CA bundle and, optionally,
+// client certificate used to establish a TLS connection to the cluster.
+type rethinkdbTLSConfig struct {
+	CACerts  []string `json:"ca_certs"`
+	CertFile string   `json:"cert_file"`
+	KeyFile  string   `json:"key_file"`
+}
+
+// secondaryIndexConfig declares a secondary index to ensure exists on the
+// sink table before Listen()ing for writes.
+type secondaryIndexConfig struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+	Multi  bool     `json:"multi"`
+	Geo    bool     `json:"geo"`
+}
+
+// rethinkdbLogger emits leveled, structured (logrus-style key=value) log
+// lines for the adaptor, so connect/reconnect, schema, and batch-write
+// events can be grepped or shipped without scraping free-form Printfs.
+// Debug-level lines are only emitted when the adaptor's `debug` config is
+// set.
+type rethinkdbLogger struct {
+	debug bool
+}
+
+func (l rethinkdbLogger) print(level, msg string, fields ...interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "time=%q level=%s msg=%q", time.Now().Format(time.RFC3339), level, msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", fields[i], fields[i+1])
+	}
+	fmt.Fprintln(os.Stdout, b.String())
+}
+
+func (l rethinkdbLogger) Debug(msg string, fields ...interface{}) {
+	if l.debug {
+		l.print("debug", msg, fields...)
+	}
+}
+
+func (l rethinkdbLogger) Info(msg string, fields ...interface{}) {
+	l.print("info", msg, fields...)
+}
+
+func (l rethinkdbLogger) Error(msg string, fields ...interface{}) {
+	l.print("error", msg, fields...)
+}
+
+// rethinkdbMetrics is a small in-process metrics registry for the adaptor,
+// exposing Prometheus-style counters/gauges so operators can monitor
+// pipelines without pulling in a full client library. All adaptor
+// instances in the process share rethinkdbMetricsRegistry.
+type rethinkdbMetrics struct {
+	mu sync.Mutex
+
+	writesTotal         map[string]int64
+	writeErrorsTotal    map[string]int64
+	batchLatencySeconds float64
+	batchLatencyCount   int64
+	changefeedLastSeen  map[string]time.Time
+}
+
+func newRethinkdbMetrics() *rethinkdbMetrics {
+	return &rethinkdbMetrics{
+		writesTotal:        map[string]int64{},
+		writeErrorsTotal:   map[string]int64{},
+		changefeedLastSeen: map[string]time.Time{},
+	}
+}
+
+// observeWrite records one batch write: n documents attempted, errs of
+// which failed, and how long the write took.
+func (m *rethinkdbMetrics) observeWrite(op string, n, errs int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writesTotal[op] += int64(n)
+	m.writeErrorsTotal[op] += int64(errs)
+	m.batchLatencySeconds += latency.Seconds()
+	m.batchLatencyCount++
+}
+
+// observeChangefeedEvent marks that a change was just processed for table,
+// so changefeed lag (time since the last observed change) can be derived.
+func (m *rethinkdbMetrics) observeChangefeedEvent(table string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changefeedLastSeen[table] = time.Now()
+}
+
+// Snapshot returns a point-in-time copy of the registered metrics, keyed by
+// Prometheus-style metric name.
+func (m *rethinkdbMetrics) Snapshot() map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := map[string]float64{}
+	for op, v := range m.writesTotal {
+		out[fmt.Sprintf(`transporter_rethinkdb_writes_total{op=%q}`, op)] = float64(v)
+	}
+	for op, v := range m.writeErrorsTotal {
+		out[fmt.Sprintf(`transporter_rethinkdb_write_errors_total{op=%q}`, op)] = float64(v)
+	}
+	if m.batchLatencyCount > 0 {
+		out["transporter_rethinkdb_batch_latency_seconds"] = m.batchLatencySeconds / float64(m.batchLatencyCount)
+	}
+	for table, lastSeen := range m.changefeedLastSeen {
+		out[fmt.Sprintf(`transporter_rethinkdb_changefeed_lag_seconds{table=%q}`, table)] = time.Since(lastSeen).Seconds()
+	}
+	return out
+}
+
+// rethinkdbMetricsRegistry holds the process-wide Rethinkdb adaptor metrics.
+var rethinkdbMetricsRegistry = newRethinkdbMetrics()
+
+// RethinkdbMetrics returns a snapshot of the adaptor's current metrics for
+// operators wiring up their own metrics endpoint.
+func RethinkdbMetrics() map[string]float64 {
+	return rethinkdbMetricsRegistry.Snapshot()
+}
+
 // Rethinkdb is an adaptor that writes metrics to rethinkdb (http://rethinkdb.com/)
 // An open-source distributed database
 type Rethinkdb struct {
@@ -22,6 +177,30 @@ type Rethinkdb struct {
 	table    string
 
 	debug bool
+	log   rethinkdbLogger
+
+	// cluster/auth/pooling options, see rethinkdbConfig
+	authKey       string
+	username      string
+	password      string
+	discoverHosts bool
+	maxOpen       int
+	maxIdle       int
+	idleTimeout   time.Duration
+	timeout       time.Duration
+	tlsConfig     *tls.Config
+
+	// bulk write options, see rethinkdbConfig
+	bulkSize      int
+	flushInterval time.Duration
+	durability    string
+	conflict      string
+
+	// schema management options, see rethinkdbConfig
+	createTable      bool
+	dropTable        bool
+	primaryKey       string
+	secondaryIndexes []secondaryIndexConfig
 
 	//
 	pipe *pipe.Pipe
@@ -29,12 +208,33 @@ type Rethinkdb struct {
 
 	// rethinkdb connection and options
 	client *gorethink.Session
+
+	// used when the adaptor is running as a source, to tail the table
+	// matching the `table` namespace (treated as a regex) and to shut the
+	// tailing goroutine down cleanly on Stop()
+	stopC   chan struct{}
+	tableWg sync.WaitGroup
+
+	// pending writes, flushed at bulkSize or flushInterval, whichever
+	// comes first; see enqueue/flush
+	batchMu    sync.Mutex
+	batch      rethinkBatch
+	flushStopC chan struct{}
+	flushWg    sync.WaitGroup
+}
+
+// rethinkBatch holds a run of same-op-type messages waiting to be written
+// together. For message.Insert/message.Update, docs holds the documents
+// themselves; for message.Delete, docs holds the primary keys to remove.
+type rethinkBatch struct {
+	op   message.OpType
+	docs []interface{}
 }
 
 // NewRethinkdb creates a new Rethinkdb database adaptor
 func NewRethinkdb(p *pipe.Pipe, path string, extra Config) (StopStartListener, error) {
 	var (
-		conf dbConfig
+		conf rethinkdbConfig
 		err  error
 	)
 	if err = extra.Construct(&conf); err != nil {
@@ -47,23 +247,310 @@ func NewRethinkdb(p *pipe.Pipe, path string, extra Config) (StopStartListener, e
 	}
 
 	r := &Rethinkdb{
-		uri:  u,
-		pipe: p,
-		path: path,
+		uri:           u,
+		pipe:          p,
+		path:          path,
+		authKey:       conf.AuthKey,
+		username:      conf.Username,
+		password:      conf.Password,
+		discoverHosts: conf.DiscoverHosts,
+		maxOpen:       conf.MaxOpen,
+		maxIdle:       conf.MaxIdle,
+	}
+
+	if conf.IdleTimeout > 0 {
+		r.idleTimeout = time.Duration(conf.IdleTimeout) * time.Second
+	} else {
+		r.idleTimeout = 10 * time.Second
+	}
+	if conf.Timeout > 0 {
+		r.timeout = time.Duration(conf.Timeout) * time.Second
+	}
+	if r.maxIdle == 0 {
+		r.maxIdle = 10
 	}
 
+	if conf.TLSConfig != nil {
+		r.tlsConfig, err = conf.TLSConfig.buildTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.bulkSize = conf.BulkSize
+	if r.bulkSize <= 0 {
+		r.bulkSize = 1000
+	}
+	if conf.FlushInterval > 0 {
+		r.flushInterval = time.Duration(conf.FlushInterval * float64(time.Second))
+	} else {
+		r.flushInterval = time.Second
+	}
+	r.durability = conf.Durability
+	r.conflict = conf.Conflict
+	if r.conflict == "" {
+		r.conflict = "error"
+	}
+
+	r.createTable = conf.CreateTable
+	r.dropTable = conf.DropTable
+	r.primaryKey = conf.PrimaryKey
+	r.secondaryIndexes = conf.SecondaryIndexes
+
 	r.database, r.table, err = extra.splitNamespace()
 	if err != nil {
 		return r, err
 	}
 	r.debug = conf.Debug
+	r.log = rethinkdbLogger{debug: r.debug}
 
 	return r, nil
 }
 
-// Start the adaptor as a source (not implemented)
-func (r *Rethinkdb) Start() error {
-	return fmt.Errorf("rethinkdb can't function as a source")
+// buildTLSConfig reads the configured CA bundle and, if given, client
+// certificate/key pair, into a *tls.Config suitable for
+// gorethink.ConnectOpts.TLSConfig.
+func (c *rethinkdbTLSConfig) buildTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if len(c.CACerts) > 0 {
+		pool := x509.NewCertPool()
+		for _, path := range c.CACerts {
+			pem, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("rethinkdb error (unable to read ca cert %s: %s)", path, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("rethinkdb error (unable to parse ca cert %s)", path)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("rethinkdb error (unable to load client cert/key: %s)", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// addresses splits the configured host into one or more host:port pairs so
+// a single URI can address an entire RethinkDB cluster
+// (e.g. "host1:28015,host2:28015").
+func (r *Rethinkdb) addresses() []string {
+	hosts := strings.Split(r.uri.Host, ",")
+	for i, h := range hosts {
+		hosts[i] = strings.TrimSpace(h)
+	}
+	return hosts
+}
+
+// Start the adaptor as a source. It matches the `table` namespace against
+// every table in the database as a regex, does an initial full-table scan
+// of the match emitting message.Insert's, and then tails that table's
+// changefeed, translating change documents into Insert/Update/Delete
+// messages in a goroutine that Stop() shuts down cleanly. Exactly one table
+// must match: a namespace that matches no table, or more than one, is
+// rejected with an error.
+func (r *Rethinkdb) Start() (err error) {
+	r.client, err = r.dial()
+	if err != nil {
+		r.pipe.Err <- err
+		return err
+	}
+
+	tables, err := r.matchingTables()
+	if err != nil {
+		r.pipe.Err <- err
+		return err
+	}
+	if len(tables) == 0 {
+		err = fmt.Errorf("rethinkdb error (no tables in database '%s' match '%s')", r.database, r.table)
+		r.pipe.Err <- err
+		return err
+	}
+	if len(tables) > 1 {
+		// messages sent down the pipe carry no source-table tag, so a
+		// reader has no way to tell which matched table a document came
+		// from. Until that plumbing exists, require the regex to resolve
+		// to a single table rather than silently interleaving several.
+		err = fmt.Errorf("rethinkdb error ('%s' matches multiple tables in database '%s' (%s); rethinkdb sources support a single table)", r.table, r.database, strings.Join(tables, ", "))
+		r.pipe.Err <- err
+		return err
+	}
+
+	r.stopC = make(chan struct{})
+	r.tableWg.Add(len(tables))
+	for _, table := range tables {
+		go r.tailTable(table)
+	}
+
+	r.tableWg.Wait()
+	return nil
+}
+
+// matchingTables returns the tables in the database whose name matches the
+// `table` namespace, which is treated as a regex. Start rejects a match of
+// more than one table, since messages carry no source-table tag a reader
+// could use to tell them apart.
+func (r *Rethinkdb) matchingTables() ([]string, error) {
+	// anchored so a plain table name (the common case) only ever matches
+	// itself; regex syntax can match more, e.g. "orders.*" also matches
+	// "orders_archive", and Start then rejects the namespace.
+	re, err := regexp.Compile("^" + r.table + "$")
+	if err != nil {
+		return nil, fmt.Errorf("rethinkdb error (invalid table regex '%s': %s)", r.table, err)
+	}
+
+	cursor, err := gorethink.Db(r.database).TableList().Run(r.client)
+	if err != nil {
+		return nil, fmt.Errorf("rethinkdb error (%s)", err)
+	}
+	defer cursor.Close()
+
+	var all []string
+	if err := cursor.All(&all); err != nil {
+		return nil, fmt.Errorf("rethinkdb error (%s)", err)
+	}
+
+	var matched []string
+	for _, t := range all {
+		if re.MatchString(t) {
+			matched = append(matched, t)
+		}
+	}
+	return matched, nil
+}
+
+// tailTable scans `table` and then follows its changefeed until Stop() is
+// called, reconnecting with an exponential backoff whenever it hits a
+// transient error.
+// A scan that fails is retried from the start until one completes; once it
+// has completed it is never repeated on reconnect - only the changefeed
+// portion is retried - so a network blip during tailing doesn't replay the
+// whole table as fresh inserts.
+func (r *Rethinkdb) tailTable(table string) {
+	defer r.tableWg.Done()
+
+	if !r.retryUntilStopped(table, "initial scan", func() error { return r.scanTable(table) }) {
+		return
+	}
+
+	r.retryUntilStopped(table, "changefeed", func() error { return r.tailChangefeed(table) })
+}
+
+// retryUntilStopped calls fn, retrying with an exponential backoff (capped
+// at 30s) on error until it succeeds or Stop() is called. It reports
+// ok=false if Stop() fired before fn ever succeeded.
+func (r *Rethinkdb) retryUntilStopped(table, action string, fn func() error) (ok bool) {
+	backoff := time.Second
+	for {
+		select {
+		case <-r.stopC:
+			return false
+		default:
+		}
+
+		err := fn()
+		if err == nil {
+			return true
+		}
+
+		r.log.Error(action+" error, retrying", "table", table, "err", err, "backoff", backoff)
+		r.pipe.Err <- NewError(ERROR, r.path, "rethinkdb error (%s)", err)
+
+		select {
+		case <-r.stopC:
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// scanTable does the initial full-table scan of `table`, emitting each row
+// as a message.Insert.
+func (r *Rethinkdb) scanTable(table string) error {
+	cursor, err := gorethink.Table(table).Run(r.client)
+	if err != nil {
+		return err
+	}
+
+	var row map[string]interface{}
+	for cursor.Next(&row) {
+		r.pipe.Send(message.NewMsg(message.Insert, row))
+		row = nil
+	}
+	if err := cursor.Err(); err != nil {
+		cursor.Close()
+		return err
+	}
+	cursor.Close()
+	return nil
+}
+
+// tailChangefeed opens a changefeed on `table` and emits a message per
+// change until stopped or a transient error is hit.
+func (r *Rethinkdb) tailChangefeed(table string) error {
+	changes, err := gorethink.Table(table).Changes().Run(r.client)
+	if err != nil {
+		return err
+	}
+	defer changes.Close()
+
+	changeC := make(chan gorethink.ChangeResponse)
+	errC := make(chan error, 1)
+	go func() {
+		defer close(changeC)
+		var change gorethink.ChangeResponse
+		for changes.Next(&change) {
+			select {
+			case changeC <- change:
+			case <-r.stopC:
+				return
+			}
+		}
+		errC <- changes.Err()
+	}()
+
+	for {
+		select {
+		case <-r.stopC:
+			changes.Close()
+			return nil
+		case err := <-errC:
+			return err
+		case change, ok := <-changeC:
+			if !ok {
+				continue
+			}
+			rethinkdbMetricsRegistry.observeChangefeedEvent(table)
+			r.emitChange(change)
+		}
+	}
+}
+
+// emitChange translates a single changefeed document into the appropriate
+// message.Insert, message.Update, or message.Delete and sends it down the
+// pipe.
+func (r *Rethinkdb) emitChange(change gorethink.ChangeResponse) {
+	switch {
+	case change.OldValue == nil && change.NewValue == nil:
+		return
+	case change.OldValue == nil:
+		r.pipe.Send(message.NewMsg(message.Insert, change.NewValue))
+	case change.NewValue == nil:
+		r.pipe.Send(message.NewMsg(message.Delete, change.OldValue))
+	default:
+		r.pipe.Send(message.NewMsg(message.Update, change.NewValue))
+	}
 }
 
 // Listen start's the adaptor's listener
@@ -74,27 +561,39 @@ func (r *Rethinkdb) Listen() (err error) {
 		return err
 	}
 
-	return r.pipe.Listen(r.applyOp)
+	r.flushStopC = make(chan struct{})
+	r.flushWg.Add(1)
+	go r.flushLoop()
+
+	err = r.pipe.Listen(r.applyOp)
+
+	close(r.flushStopC)
+	r.flushWg.Wait()
+	r.flush()
+
+	return err
 }
 
 // Stop the adaptor
 func (r *Rethinkdb) Stop() error {
 	r.pipe.Stop()
+	if r.stopC != nil {
+		close(r.stopC)
+	}
+	r.tableWg.Wait()
 	return nil
 }
 
-// applyOp applies one operation to the database
+// applyOp enqueues one operation into the pending batch for its op-type,
+// flushing whatever was pending whenever the op-type changes or the batch
+// hits bulkSize. The actual write happens in flushLocked, either from here
+// (via enqueue), from flushLoop, or from Listen's final flush once the pipe
+// stops.
 func (r *Rethinkdb) applyOp(msg *message.Msg) (*message.Msg, error) {
-	var (
-		resp gorethink.WriteResponse
-		err  error
-	)
-
 	if !msg.IsMap() {
 		r.pipe.Err <- NewError(ERROR, r.path, "rethinkdb error (document must be a json document)", msg.Data)
 		return msg, nil
 	}
-	doc := msg.Map()
 
 	switch msg.Op {
 	case message.Delete:
@@ -103,58 +602,301 @@ func (r *Rethinkdb) applyOp(msg *message.Msg) (*message.Msg, error) {
 			r.pipe.Err <- NewError(ERROR, r.path, "rethinkdb error (cannot delete an object with a nil id)", msg.Data)
 			return msg, nil
 		}
-		resp, err = gorethink.Table(r.table).Get(id).Delete().RunWrite(r.client)
+		r.enqueue(message.Delete, id)
 	case message.Insert:
-		resp, err = gorethink.Table(r.table).Insert(doc).RunWrite(r.client)
+		r.enqueue(message.Insert, msg.Map())
 	case message.Update:
-		resp, err = gorethink.Table(r.table).Insert(doc, gorethink.InsertOpts{Conflict: "replace"}).RunWrite(r.client)
+		r.enqueue(message.Update, msg.Map())
 	}
-	if err != nil {
-		r.pipe.Err <- NewError(ERROR, r.path, "rethinkdb error (%s)", err)
-		return msg, nil
+
+	return msg, nil
+}
+
+// enqueue adds doc to the pending batch for op, flushing the batch first
+// if it's non-empty and holds a different op-type, or once it fills up.
+func (r *Rethinkdb) enqueue(op message.OpType, doc interface{}) {
+	r.batchMu.Lock()
+	defer r.batchMu.Unlock()
+
+	if len(r.batch.docs) > 0 && r.batch.op != op {
+		r.flushLocked()
 	}
 
-	err = r.handleResponse(&resp)
+	r.batch.op = op
+	r.batch.docs = append(r.batch.docs, doc)
+
+	if len(r.batch.docs) >= r.bulkSize {
+		r.flushLocked()
+	}
+}
+
+// flushLoop flushes the pending batch every flushInterval, so a batch
+// smaller than bulkSize still gets written in a timely fashion.
+func (r *Rethinkdb) flushLoop() {
+	defer r.flushWg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.flush()
+		case <-r.flushStopC:
+			return
+		}
+	}
+}
+
+// flush writes out whatever is currently pending, if anything.
+func (r *Rethinkdb) flush() {
+	r.batchMu.Lock()
+	defer r.batchMu.Unlock()
+	r.flushLocked()
+}
+
+// flushLocked performs the actual batched write; callers must hold batchMu.
+func (r *Rethinkdb) flushLocked() {
+	if len(r.batch.docs) == 0 {
+		return
+	}
+	op := r.batch.op
+	docs := r.batch.docs
+	r.batch = rethinkBatch{}
+
+	var (
+		resp gorethink.WriteResponse
+		err  error
+	)
+	start := time.Now()
+
+	switch op {
+	case message.Delete:
+		resp, err = gorethink.Table(r.table).GetAll(docs...).Delete(gorethink.DeleteOpts{
+			Durability:    r.durability,
+			ReturnChanges: true,
+		}).RunWrite(r.client)
+	case message.Insert:
+		resp, err = gorethink.Table(r.table).Insert(docs, gorethink.InsertOpts{
+			Conflict:      r.conflict,
+			Durability:    r.durability,
+			ReturnChanges: true,
+		}).RunWrite(r.client)
+	case message.Update:
+		resp, err = gorethink.Table(r.table).Insert(docs, gorethink.InsertOpts{
+			Conflict:      "replace",
+			Durability:    r.durability,
+			ReturnChanges: true,
+		}).RunWrite(r.client)
+	}
+	latency := time.Since(start)
 	if err != nil {
+		rethinkdbMetricsRegistry.observeWrite(batchOpName(op), len(docs), len(docs), latency)
+		r.log.Error("batch write failed", "op", batchOpName(op), "docs", len(docs), "err", err)
 		r.pipe.Err <- NewError(ERROR, r.path, "rethinkdb error (%s)", err)
+		return
 	}
 
-	return msg, nil
+	rethinkdbMetricsRegistry.observeWrite(batchOpName(op), len(docs), resp.Errors, latency)
+	r.log.Info("batch write",
+		"op", batchOpName(op),
+		"docs", len(docs),
+		"inserted", resp.Inserted,
+		"replaced", resp.Replaced,
+		"errors", resp.Errors,
+		"first_error", resp.FirstError,
+		"latency", latency,
+	)
+
+	r.handleBatchResponse(op, &resp)
 }
 
-func (r *Rethinkdb) setupClient() (*gorethink.Session, error) {
-	// set up the clientConfig, we need host:port, username, password, and database name
-	if r.debug {
-		fmt.Printf("Connecting to %s\n", r.uri.Host)
+// handleBatchResponse reports batch write outcomes. ReturnChanges is on, so
+// resp.Changes carries one entry per document with its own Error field
+// (empty on success) - we report exactly the documents that failed,
+// ignoring the harmless "Duplicate primary key" conflicts one gets from
+// replaying rows that already exist, rather than guessing which of the
+// batch's documents were responsible for resp.Errors.
+func (r *Rethinkdb) handleBatchResponse(op message.OpType, resp *gorethink.WriteResponse) {
+	if resp.Errors == 0 {
+		return
 	}
-	client, err := gorethink.Connect(gorethink.ConnectOpts{
-		Address:     r.uri.Host,
-		MaxIdle:     10,
-		IdleTimeout: time.Second * 10,
-	})
+
+	for _, change := range resp.Changes {
+		if change.Error == "" || strings.Contains(change.Error, "Duplicate primary key") {
+			continue
+		}
+		data := change.NewValue
+		if data == nil {
+			data = change.OldValue
+		}
+		msg := fmt.Sprintf("rethinkdb batch %s error (%s)", batchOpName(op), change.Error)
+		r.pipe.Err <- NewError(ERROR, r.path, msg, data)
+	}
+}
+
+// batchOpName renders an op-type for log/error messages.
+func batchOpName(op message.OpType) string {
+	switch op {
+	case message.Insert:
+		return "insert"
+	case message.Update:
+		return "update"
+	case message.Delete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// setupClient connects to rethinkdb and, if configured, manages the sink
+// table's schema: it only drops the table when drop_table is set, and only
+// creates the table (and declared secondary indexes) when it's missing,
+// rather than unconditionally destroying and recreating it on every Listen().
+func (r *Rethinkdb) setupClient() (*gorethink.Session, error) {
+	client, err := r.dial()
 	if err != nil {
-		return nil, fmt.Errorf("unable to connect: %s", err)
+		return nil, err
 	}
 
-	if r.debug {
-		fmt.Printf("dropping and creating table '%s' on database '%s'\n", r.table, r.database)
+	if r.dropTable {
+		r.log.Info("dropping table", "table", r.table, "database", r.database)
+		if _, err := gorethink.Db(r.database).TableDrop(r.table).RunWrite(client); err != nil {
+			return nil, fmt.Errorf("rethinkdb error (unable to drop table %s: %s)", r.table, err)
+		}
+	}
+
+	if r.createTable {
+		if err := r.ensureTable(client); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.ensureIndexes(client); err != nil {
+		return nil, err
 	}
-	gorethink.Db(r.database).TableDrop(r.table).RunWrite(client)
-	gorethink.Db(r.database).TableCreate(r.table).RunWrite(client)
 
-	client.Use(r.database)
 	return client, nil
 }
 
-// handleresponse takes the rethink response and turn it into something we can consume elsewhere
-func (r *Rethinkdb) handleResponse(resp *gorethink.WriteResponse) error {
-	if resp.Errors != 0 {
-		if !strings.Contains(resp.FirstError, "Duplicate primary key") { // we don't care about this error
-			if r.debug {
-				fmt.Printf("Reported %d errors\n", resp.Errors)
-			}
-			return fmt.Errorf("%s\n%s", "problem inserting docs", resp.FirstError)
+// ensureTable creates the sink table if it doesn't already exist.
+func (r *Rethinkdb) ensureTable(client *gorethink.Session) error {
+	cursor, err := gorethink.Db(r.database).TableList().Run(client)
+	if err != nil {
+		return fmt.Errorf("rethinkdb error (%s)", err)
+	}
+	var tables []string
+	err = cursor.All(&tables)
+	cursor.Close()
+	if err != nil {
+		return fmt.Errorf("rethinkdb error (%s)", err)
+	}
+
+	for _, t := range tables {
+		if t == r.table {
+			return nil
 		}
 	}
+
+	r.log.Info("creating table", "table", r.table, "database", r.database)
+
+	opts := gorethink.TableCreateOpts{}
+	if r.primaryKey != "" {
+		opts.PrimaryKey = r.primaryKey
+	}
+	if _, err := gorethink.Db(r.database).TableCreate(r.table, opts).RunWrite(client); err != nil {
+		return fmt.Errorf("rethinkdb error (unable to create table %s: %s)", r.table, err)
+	}
 	return nil
 }
+
+// ensureIndexes creates any declared secondary indexes that don't already
+// exist on the sink table, and waits for them to become ready.
+func (r *Rethinkdb) ensureIndexes(client *gorethink.Session) error {
+	if len(r.secondaryIndexes) == 0 {
+		return nil
+	}
+
+	cursor, err := gorethink.Db(r.database).Table(r.table).IndexList().Run(client)
+	if err != nil {
+		return fmt.Errorf("rethinkdb error (%s)", err)
+	}
+	var existing []string
+	err = cursor.All(&existing)
+	cursor.Close()
+	if err != nil {
+		return fmt.Errorf("rethinkdb error (%s)", err)
+	}
+
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[name] = true
+	}
+
+	for _, idx := range r.secondaryIndexes {
+		if have[idx.Name] {
+			continue
+		}
+		if err := r.createIndex(client, idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIndex creates a single secondary index and waits for it to build.
+func (r *Rethinkdb) createIndex(client *gorethink.Session, idx secondaryIndexConfig) error {
+	if len(idx.Fields) == 0 {
+		return fmt.Errorf("rethinkdb error (secondary index '%s' has no fields)", idx.Name)
+	}
+
+	r.log.Info("creating secondary index", "index", idx.Name, "table", r.table)
+
+	opts := gorethink.IndexCreateOpts{Multi: idx.Multi, Geo: idx.Geo}
+
+	var indexFunc interface{}
+	if len(idx.Fields) == 1 {
+		indexFunc = gorethink.Row.Field(idx.Fields[0])
+	} else {
+		fields := make([]interface{}, len(idx.Fields))
+		for i, f := range idx.Fields {
+			fields[i] = gorethink.Row.Field(f)
+		}
+		indexFunc = fields
+	}
+
+	if _, err := gorethink.Db(r.database).Table(r.table).IndexCreateFunc(idx.Name, indexFunc, opts).RunWrite(client); err != nil {
+		return fmt.Errorf("rethinkdb error (unable to create index '%s': %s)", idx.Name, err)
+	}
+
+	if _, err := gorethink.Db(r.database).Table(r.table).IndexWait(idx.Name).Run(client); err != nil {
+		return fmt.Errorf("rethinkdb error (unable to wait for index '%s': %s)", idx.Name, err)
+	}
+
+	return nil
+}
+
+// dial opens the connection to rethinkdb, without touching any table schema.
+func (r *Rethinkdb) dial() (*gorethink.Session, error) {
+	// set up the clientConfig, we need host:port, username, password, and database name
+	addresses := r.addresses()
+	r.log.Info("connecting", "addresses", strings.Join(addresses, ","))
+	client, err := gorethink.Connect(gorethink.ConnectOpts{
+		Addresses:     addresses,
+		AuthKey:       r.authKey,
+		Username:      r.username,
+		Password:      r.password,
+		DiscoverHosts: r.discoverHosts,
+		MaxOpen:       r.maxOpen,
+		MaxIdle:       r.maxIdle,
+		IdleTimeout:   r.idleTimeout,
+		Timeout:       r.timeout,
+		TLSConfig:     r.tlsConfig,
+	})
+	if err != nil {
+		r.log.Error("connect failed", "addresses", strings.Join(addresses, ","), "err", err)
+		return nil, fmt.Errorf("unable to connect: %s", err)
+	}
+	client.Use(r.database)
+	return client, nil
+}
+